@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"os"
 	"runtime"
 	"sync"
@@ -70,6 +71,35 @@ const (
 
 var errTxNotFound = errors.New("transaction not found")
 
+var (
+	// ErrBlockNotFound is matched by the error returned if the block to trace,
+	// or its parent, is not available.
+	ErrBlockNotFound = errors.New("block not found")
+
+	// ErrExecutionTimeout is returned if tracing a single transaction took
+	// longer than the configured timeout.
+	ErrExecutionTimeout = errors.New("execution timeout")
+
+	// ErrTraceCancelled is returned if the caller cancelled the request, or its
+	// deadline passed, while a transaction was being traced.
+	ErrTraceCancelled = errors.New("tracing cancelled")
+
+	// ErrMaxDepthExceeded is returned if the traced execution nested calls
+	// deeper than the configured maximum depth.
+	ErrMaxDepthExceeded = errors.New("max call depth exceeded")
+)
+
+// blockNotFoundError reports a missing block with the message clients have
+// always received, while matching ErrBlockNotFound.
+type blockNotFoundError struct {
+	msg string
+}
+
+func (e *blockNotFoundError) Error() string { return e.msg }
+
+// Is implements the errors.Is interface.
+func (e *blockNotFoundError) Is(target error) bool { return target == ErrBlockNotFound }
+
 // StateReleaseFunc is used to deallocate resources held by constructing a
 // historical state for tracing purposes.
 type StateReleaseFunc func()
@@ -114,7 +144,7 @@ func (api *API) blockByNumber(ctx context.Context, number rpc.BlockNumber) (*typ
 		return nil, err
 	}
 	if block == nil {
-		return nil, fmt.Errorf("block #%d not found", number)
+		return nil, &blockNotFoundError{fmt.Sprintf("block #%d not found", number)}
 	}
 	return block, nil
 }
@@ -127,7 +157,7 @@ func (api *API) blockByHash(ctx context.Context, hash common.Hash) (*types.Block
 		return nil, err
 	}
 	if block == nil {
-		return nil, fmt.Errorf("block %s not found", hash.Hex())
+		return nil, &blockNotFoundError{fmt.Sprintf("block %s not found", hash.Hex())}
 	}
 	return block, nil
 }
@@ -148,12 +178,36 @@ func (api *API) blockByNumberAndHash(ctx context.Context, number rpc.BlockNumber
 	return api.blockByHash(ctx, hash)
 }
 
+// blockByNumberOrHash resolves the block referenced by an RPC block specifier.
+// Tracing on top of the pending block is not supported.
+func (api *API) blockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.blockByHash(ctx, hash)
+	}
+	number, ok := blockNrOrHash.Number()
+	if !ok {
+		return nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	if number == rpc.PendingBlockNumber {
+		// We don't have access to the miner here. For tracing 'future' transactions,
+		// it can be done with block- and state-overrides instead, which offers
+		// more flexibility and stability than trying to trace on 'pending', since
+		// the contents of 'pending' is unstable and probably not a true representation
+		// of what the next actual block is likely to contain.
+		return nil, errors.New("tracing on top of pending is not supported")
+	}
+	return api.blockByNumber(ctx, number)
+}
+
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*logger.Config
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	// MaxDepth aborts the trace if calls nest deeper than it. The top
+	// level call is at depth 0. Execution itself is not limited.
+	MaxDepth *uint64
 	// Config specific to given tracer. Note struct logger
 	// config are historically embedded in main object.
 	TracerConfig json.RawMessage
@@ -167,6 +221,33 @@ type TraceCallConfig struct {
 	BlockOverrides *ethapi.BlockOverrides
 }
 
+// TransactionOverrides is the collection of fields of a historical transaction
+// that can be replaced before tracing it again.
+type TransactionOverrides struct {
+	Value    *hexutil.Big    `json:"value"`
+	Gas      *hexutil.Uint64 `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+}
+
+// Apply overrides the given fields into the message.
+func (diff *TransactionOverrides) Apply(msg *core.Message) {
+	if diff == nil {
+		return
+	}
+	if diff.Value != nil {
+		msg.Value = diff.Value.ToInt()
+	}
+	if diff.Gas != nil {
+		msg.GasLimit = uint64(*diff.Gas)
+	}
+	if diff.GasPrice != nil {
+		// A legacy gas price pays the full amount regardless of the base fee
+		msg.GasPrice = diff.GasPrice.ToInt()
+		msg.GasFeeCap = msg.GasPrice
+		msg.GasTipCap = msg.GasPrice
+	}
+}
+
 // StdTraceConfig holds extra parameters to standard-json trace functions.
 type StdTraceConfig struct {
 	logger.Config
@@ -277,15 +358,18 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 						TxIndex:     i,
 						TxHash:      tx.Hash(),
 					}
-					res, err := api.traceTx(ctx, msg, txctx, blockCtx, task.statedb, config)
+					res, err := api.traceBlockTx(ctx, msg, txctx, blockCtx, task.statedb, config)
 					if err != nil {
 						task.results[i] = &txTraceResult{TxHash: tx.Hash(), Error: err.Error()}
 						log.Warn("Tracing failed", "hash", tx.Hash(), "block", task.block.NumberU64(), "err", err)
-						break
+						if !errors.Is(err, ErrExecutionTimeout) {
+							break
+						}
+					} else {
+						task.results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
 					}
 					// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 					task.statedb.Finalise(api.backend.ChainConfig().IsEIP158(task.block.Number()))
-					task.results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
 				}
 				// Tracing state is used up, queue it for de-referencing. Note the
 				// state is the parent state of trace block, use block.number-1 as
@@ -475,7 +559,7 @@ func (api *API) TraceBlockFromFile(ctx context.Context, file string, config *Tra
 func (api *API) TraceBadBlock(ctx context.Context, hash common.Hash, config *TraceConfig) ([]*txTraceResult, error) {
 	block := rawdb.ReadBadBlock(api.backend.ChainDb(), hash)
 	if block == nil {
-		return nil, fmt.Errorf("bad block %#x not found", hash)
+		return nil, &blockNotFoundError{fmt.Sprintf("bad block %#x not found", hash)}
 	}
 	return api.traceBlock(ctx, block, config)
 }
@@ -500,7 +584,7 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 		block = rawdb.ReadBadBlock(api.backend.ChainDb(), hash)
 	}
 	if block == nil {
-		return nil, fmt.Errorf("block %#x not found", hash)
+		return nil, &blockNotFoundError{fmt.Sprintf("block %#x not found", hash)}
 	}
 	if block.NumberU64() == 0 {
 		return nil, errors.New("genesis is not traceable")
@@ -520,7 +604,7 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 	defer release()
 
 	var (
-		roots              []common.Hash
+		roots              = make([]common.Hash, 0, len(block.Transactions()))
 		signer             = types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
 		chainConfig        = api.backend.ChainConfig()
 		vmctx              = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
@@ -528,7 +612,7 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 	)
 	for i, tx := range block.Transactions() {
 		if err := ctx.Err(); err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%w: %v", ErrTraceCancelled, err)
 		}
 		var (
 			msg, _    = core.TransactionToMessage(tx, signer, block.BaseFee())
@@ -559,7 +643,7 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 func (api *API) StandardTraceBadBlockToFile(ctx context.Context, hash common.Hash, config *StdTraceConfig) ([]string, error) {
 	block := rawdb.ReadBadBlock(api.backend.ChainDb(), hash)
 	if block == nil {
-		return nil, fmt.Errorf("bad block %#x not found", hash)
+		return nil, &blockNotFoundError{fmt.Sprintf("bad block %#x not found", hash)}
 	}
 	return api.standardTraceBlockToFile(ctx, block, config)
 }
@@ -604,6 +688,9 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 		results   = make([]*txTraceResult, len(txs))
 	)
 	for i, tx := range txs {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrTraceCancelled, err)
+		}
 		// Generate the next state snapshot fast without tracing
 		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
 		txctx := &Context{
@@ -612,11 +699,16 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 			TxIndex:     i,
 			TxHash:      tx.Hash(),
 		}
-		res, err := api.traceTx(ctx, msg, txctx, blockCtx, statedb, config)
-		if err != nil {
+		res, err := api.traceBlockTx(ctx, msg, txctx, blockCtx, statedb, config)
+		switch {
+		case errors.Is(err, ErrExecutionTimeout):
+			results[i] = &txTraceResult{TxHash: tx.Hash(), Error: err.Error()}
+			log.Warn("Tracing timed out", "hash", tx.Hash(), "block", block.NumberU64())
+		case err != nil:
 			return nil, err
+		default:
+			results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
 		}
-		results[i] = &txTraceResult{TxHash: tx.Hash(), Result: res}
 		// Finalize the state so any modifications are written to the trie
 		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
 		statedb.Finalise(is158)
@@ -673,7 +765,7 @@ txloop:
 		task := &txTraceTask{statedb: statedb.Copy(), index: i}
 		select {
 		case <-ctx.Done():
-			failed = ctx.Err()
+			failed = fmt.Errorf("%w: %v", ErrTraceCancelled, ctx.Err())
 			break txloop
 		case jobs <- task:
 		}
@@ -681,19 +773,23 @@ txloop:
 		// Generate the next state snapshot fast without tracing
 		msg, _ := core.TransactionToMessage(tx, signer, block.BaseFee())
 		statedb.SetTxContext(tx.Hash(), i)
-		vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.backend.ChainConfig(), vm.Config{})
-		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit)); err != nil {
+		if err := api.applyTx(msg, blockCtx, statedb); err != nil {
 			failed = err
 			break txloop
 		}
 		// Finalize the state so any modifications are written to the trie
 		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
-		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
+		statedb.Finalise(api.backend.ChainConfig().IsEIP158(block.Number()))
 	}
 
 	close(jobs)
 	pend.Wait()
 
+	// The workers record per-transaction errors, so a cancellation that arrived
+	// after the last task was handed out would otherwise go unnoticed
+	if failed == nil && ctx.Err() != nil {
+		failed = fmt.Errorf("%w: %v", ErrTraceCancelled, ctx.Err())
+	}
 	// If execution failed in between, abort
 	if failed != nil {
 		return nil, failed
@@ -826,6 +922,19 @@ func containsTx(block *types.Block, hash common.Hash) bool {
 // TraceTransaction returns the structured logs created during the execution of EVM
 // and returns them as a JSON object.
 func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *TraceConfig) (interface{}, error) {
+	return api.traceTransaction(ctx, hash, nil, config)
+}
+
+// TraceTransactionWithOverrides re-executes a historical transaction with some
+// of its fields replaced, on top of the same state it was originally executed
+// on, and returns the structured logs created during the execution of EVM.
+func (api *API) TraceTransactionWithOverrides(ctx context.Context, hash common.Hash, overrides *TransactionOverrides, config *TraceConfig) (interface{}, error) {
+	return api.traceTransaction(ctx, hash, overrides, config)
+}
+
+// traceTransaction retrieves the pre-state of a mined transaction, applies the
+// given overrides to it and traces its execution.
+func (api *API) traceTransaction(ctx context.Context, hash common.Hash, overrides *TransactionOverrides, config *TraceConfig) (interface{}, error) {
 	tx, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
 	if err != nil {
 		return nil, err
@@ -838,25 +947,49 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 	if blockNumber == 0 {
 		return nil, errors.New("genesis is not traceable")
 	}
-	reexec := defaultTraceReexec
-	if config != nil && config.Reexec != nil {
-		reexec = *config.Reexec
-	}
 	block, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
 	if err != nil {
 		return nil, err
 	}
-	msg, vmctx, statedb, release, err := api.backend.StateAtTransaction(ctx, block, int(index), reexec)
+	return api.traceTxAt(ctx, block, int(index), overrides, config)
+}
+
+// TraceTransactionByIndex returns the structured logs created during the execution
+// of the transaction at the given position of a block, on top of the state left
+// by the transactions preceding it.
+func (api *API) TraceTransactionByIndex(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, index hexutil.Uint, config *TraceConfig) (interface{}, error) {
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	if txs := block.Transactions(); int(index) >= len(txs) {
+		return nil, fmt.Errorf("transaction index %d out of range, block #%d has %d transactions", index, block.NumberU64(), len(txs))
+	}
+	return api.traceTxAt(ctx, block, int(index), nil, config)
+}
+
+// traceTxAt regenerates the state in which the transaction at the given index
+// of the block was executed, applies the overrides to it and traces it.
+func (api *API) traceTxAt(ctx context.Context, block *types.Block, index int, overrides *TransactionOverrides, config *TraceConfig) (interface{}, error) {
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	msg, vmctx, statedb, release, err := api.backend.StateAtTransaction(ctx, block, index, reexec)
 	if err != nil {
 		return nil, err
 	}
 	defer release()
+	overrides.Apply(msg)
 
 	txctx := &Context{
-		BlockHash:   blockHash,
+		BlockHash:   block.Hash(),
 		BlockNumber: block.Number(),
-		TxIndex:     int(index),
-		TxHash:      hash,
+		TxIndex:     index,
+		TxHash:      block.Transactions()[index].Hash(),
 	}
 	return api.traceTx(ctx, msg, txctx, vmctx, statedb, config)
 }
@@ -866,47 +999,16 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // top of the provided block and returns them as a JSON object.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
 	// Try to retrieve the specified block
-	var (
-		err   error
-		block *types.Block
-	)
-	if hash, ok := blockNrOrHash.Hash(); ok {
-		block, err = api.blockByHash(ctx, hash)
-	} else if number, ok := blockNrOrHash.Number(); ok {
-		if number == rpc.PendingBlockNumber {
-			// We don't have access to the miner here. For tracing 'future' transactions,
-			// it can be done with block- and state-overrides instead, which offers
-			// more flexibility and stability than trying to trace on 'pending', since
-			// the contents of 'pending' is unstable and probably not a true representation
-			// of what the next actual block is likely to contain.
-			return nil, errors.New("tracing on top of pending is not supported")
-		}
-		block, err = api.blockByNumber(ctx, number)
-	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
-	}
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
 	if err != nil {
 		return nil, err
 	}
-	// try to recompute the state
-	reexec := defaultTraceReexec
-	if config != nil && config.Reexec != nil {
-		reexec = *config.Reexec
-	}
-	statedb, release, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
+	statedb, vmctx, release, err := api.callState(ctx, block, config)
 	if err != nil {
 		return nil, err
 	}
 	defer release()
 
-	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-	// Apply the customization rules if required.
-	if config != nil {
-		if err := config.StateOverrides.Apply(statedb); err != nil {
-			return nil, err
-		}
-		config.BlockOverrides.Apply(&vmctx)
-	}
 	// Execute the trace
 	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.BaseFee())
 	if err != nil {
@@ -920,6 +1022,65 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
 }
 
+// TraceRawTransaction decodes a signed transaction and traces it as if it was
+// included on top of the provided block, without broadcasting it. The sender is
+// recovered from the signature, which has to be valid for the traced block.
+// Since the gas limit is signed, transactions above the RPC gas cap are rejected
+// rather than capped.
+func (api *API) TraceRawTransaction(ctx context.Context, input hexutil.Bytes, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return nil, err
+	}
+	if gasCap := api.backend.RPCGasCap(); gasCap != 0 && tx.Gas() > gasCap {
+		return nil, fmt.Errorf("transaction gas %d exceeds the RPC gas cap %d", tx.Gas(), gasCap)
+	}
+	// Try to retrieve the specified block
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.MakeSigner(api.backend.ChainConfig(), block.Number(), block.Time())
+	msg, err := core.TransactionToMessage(tx, signer, block.BaseFee())
+	if err != nil {
+		return nil, err
+	}
+	statedb, vmctx, release, err := api.callState(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var traceConfig *TraceConfig
+	if config != nil {
+		traceConfig = &config.TraceConfig
+	}
+	return api.traceTx(ctx, msg, &Context{TxHash: tx.Hash()}, vmctx, statedb, traceConfig)
+}
+
+// callState recomputes the state at the given block and returns it along with
+// the block context to trace a call in, with the overrides of the config applied.
+func (api *API) callState(ctx context.Context, block *types.Block, config *TraceCallConfig) (*state.StateDB, vm.BlockContext, StateReleaseFunc, error) {
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, release, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
+	if err != nil {
+		return nil, vm.BlockContext{}, nil, err
+	}
+	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
+	// Apply the customization rules if required.
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			release()
+			return nil, vm.BlockContext{}, nil, err
+		}
+		config.BlockOverrides.Apply(&vmctx)
+	}
+	return statedb, vmctx, release, nil
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
@@ -941,6 +1102,9 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 			return nil, err
 		}
 	}
+	if config.MaxDepth != nil {
+		tracer = &depthLimiter{Tracer: tracer, limit: *config.MaxDepth}
+	}
 	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Tracer: tracer, NoBaseFee: true})
 
 	// Define a meaningful timeout of a single transaction trace
@@ -952,11 +1116,18 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 	go func() {
 		<-deadlineCtx.Done()
-		if errors.Is(deadlineCtx.Err(), context.DeadlineExceeded) {
-			tracer.Stop(errors.New("execution timeout"))
-			// Stop evm execution. Note cancellation is not necessarily immediate.
-			vmenv.Cancel()
+		var reason error
+		switch {
+		case ctx.Err() != nil:
+			reason = ErrTraceCancelled
+		case errors.Is(deadlineCtx.Err(), context.DeadlineExceeded):
+			reason = ErrExecutionTimeout
+		default:
+			return // Tracing finished
 		}
+		tracer.Stop(reason)
+		// Stop evm execution. Note cancellation is not necessarily immediate.
+		vmenv.Cancel()
 	}()
 	defer cancel()
 
@@ -968,6 +1139,68 @@ func (api *API) traceTx(ctx context.Context, message *core.Message, txctx *Conte
 	return tracer.GetResult()
 }
 
+// depthLimiter wraps a tracer and stops it once calls nest deeper than the
+// limit. Scopes entered past the limit are not forwarded to the wrapped tracer.
+type depthLimiter struct {
+	Tracer
+	limit uint64
+	depth uint64
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *depthLimiter) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.depth++
+	if t.depth > t.limit {
+		if t.depth == t.limit+1 {
+			t.Tracer.Stop(fmt.Errorf("%w: %d", ErrMaxDepthExceeded, t.limit))
+		}
+		return
+	}
+	t.Tracer.CaptureEnter(typ, from, to, input, gas, value)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *depthLimiter) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.depth--
+	if t.depth >= t.limit {
+		return
+	}
+	t.Tracer.CaptureExit(output, gasUsed, err)
+}
+
+// traceBlockTx traces a single transaction of a block on top of the given state.
+// If the trace runs over its own timeout, the state is rewound and the message is
+// re-executed without tracing, so that the following transactions of the block
+// still run on the correct state. The timeout is reported as ErrExecutionTimeout.
+// If instead the caller's context is done, ErrTraceCancelled is returned.
+func (api *API) traceBlockTx(ctx context.Context, message *core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+	snapshot := statedb.Snapshot()
+	res, err := api.traceTx(ctx, message, txctx, vmctx, statedb, config)
+	if errors.Is(err, ErrTraceCancelled) {
+		return nil, err
+	}
+	if !errors.Is(err, ErrExecutionTimeout) {
+		return res, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTraceCancelled, err)
+	}
+	statedb.RevertToSnapshot(snapshot)
+	if err := api.applyTx(message, vmctx, statedb); err != nil {
+		return nil, err
+	}
+	return nil, ErrExecutionTimeout
+}
+
+// applyTx executes the given message on top of the provided state without
+// any tracing.
+func (api *API) applyTx(message *core.Message, vmctx vm.BlockContext, statedb *state.StateDB) error {
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(message), statedb, api.backend.ChainConfig(), vm.Config{})
+	_, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.GasLimit))
+	return err
+}
+
 // APIs return the collection of RPC services the tracer package offers.
 func APIs(backend Backend) []rpc.API {
 	// Append all the local APIs and return
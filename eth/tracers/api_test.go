@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -69,6 +70,9 @@ func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i i
 		engine:      ethash.NewFaker(),
 		chaindb:     rawdb.NewMemoryDatabase(),
 	}
+	if gspec.Config.TerminalTotalDifficultyPassed {
+		backend.engine = beacon.NewFaker()
+	}
 	// Generate blocks for testing
 	_, blocks, _ := core.GenerateChainWithGenesis(gspec, backend.engine, n, generator)
 
@@ -249,7 +253,7 @@ func TestTraceCall(t *testing.T) {
 				Value: (*hexutil.Big)(big.NewInt(1000)),
 			},
 			config:    nil,
-			expectErr: fmt.Errorf("block #%d not found", genBlocks+1),
+			expectErr: &blockNotFoundError{fmt.Sprintf("block #%d not found", genBlocks+1)},
 			//expect:    nil,
 		},
 		// Standard JSON trace upon the latest block
@@ -320,6 +324,89 @@ func TestTraceCall(t *testing.T) {
 	}
 }
 
+func TestTraceRawTransaction(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts and a contract returning its caller:
+	// CALLER PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	accounts := newAccounts(2)
+	contract := common.HexToAddress("0xc0ffee")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+			contract:         {Balance: common.Big0, Code: common.Hex2Bytes("3360005260206000f3")},
+		},
+	}
+	signer := types.LatestSigner(genesis.Config)
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		// Transfer from account[0] to account[1]
+		tx, _ := types.SignNewTx(accounts[0].key, signer, &types.LegacyTx{Nonce: uint64(i), To: &accounts[1].addr, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()})
+		b.AddTx(tx)
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	sign := func(signer types.Signer, nonce uint64, gas uint64) hexutil.Bytes {
+		tx, _ := types.SignNewTx(accounts[0].key, signer, &types.LegacyTx{Nonce: nonce, To: &contract, Gas: gas, GasPrice: big.NewInt(params.GWei)})
+		raw, _ := tx.MarshalBinary()
+		return raw
+	}
+	var testSuite = []struct {
+		input     hexutil.Bytes
+		expectErr error
+		retValue  string
+	}{
+		// Valid transaction, the sender is recovered from the signature
+		{
+			input:    sign(signer, 1, 100000),
+			retValue: fmt.Sprintf("%x", common.BytesToHash(accounts[0].addr.Bytes())),
+		},
+		// Nonce already used in block 1
+		{
+			input:     sign(signer, 0, 100000),
+			expectErr: core.ErrNonceTooLow,
+		},
+		// Signature for another chain
+		{
+			input:     sign(types.LatestSignerForChainID(big.NewInt(2)), 1, 100000),
+			expectErr: types.ErrInvalidChainId,
+		},
+		// Gas limit above the RPC gas cap
+		{
+			input:     sign(signer, 1, backend.RPCGasCap()+1),
+			expectErr: fmt.Errorf("transaction gas %d exceeds the RPC gas cap %d", backend.RPCGasCap()+1, backend.RPCGasCap()),
+		},
+		// Undecodable input
+		{
+			input:     hexutil.Bytes{0x01},
+			expectErr: errors.New("typed transaction too short"),
+		},
+	}
+	for i, testspec := range testSuite {
+		result, err := api.TraceRawTransaction(context.Background(), testspec.input, rpc.BlockNumberOrHashWithNumber(1), nil)
+		if testspec.expectErr != nil {
+			if err == nil || (!errors.Is(err, testspec.expectErr) && err.Error() != testspec.expectErr.Error()) {
+				t.Errorf("test %d: error mismatch, want %v, have %v", i, testspec.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to trace transaction: %v", i, err)
+			continue
+		}
+		var have *logger.ExecutionResult
+		if err := json.Unmarshal(result.(json.RawMessage), &have); err != nil {
+			t.Errorf("test %d: failed to unmarshal result %v", i, err)
+			continue
+		}
+		if have.Failed || have.ReturnValue != testspec.retValue {
+			t.Errorf("test %d: result mismatch, want return %q, have failed %v return %q", i, testspec.retValue, have.Failed, have.ReturnValue)
+		}
+	}
+}
+
 func TestTraceTransaction(t *testing.T) {
 	t.Parallel()
 
@@ -368,6 +455,175 @@ func TestTraceTransaction(t *testing.T) {
 	}
 }
 
+func TestTraceTransactionWithOverrides(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts and a contract returning the call value and
+	// gas price: CALLVALUE PUSH1 0 MSTORE GASPRICE PUSH1 32 MSTORE PUSH1 64 PUSH1 0 RETURN
+	accounts := newAccounts(1)
+	contract := common.HexToAddress("0xc0ffee")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			contract:         {Balance: common.Big0, Code: common.Hex2Bytes("346000523a60205260406000f3")},
+		},
+	}
+	var (
+		target  common.Hash
+		baseFee *big.Int
+		signer  = types.HomesteadSigner{}
+	)
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		baseFee = b.BaseFee()
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), contract, big.NewInt(1000), 100000, baseFee, nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		target = tx.Hash()
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	var (
+		value    = big.NewInt(2000)
+		gasPrice = new(big.Int).Mul(baseFee, big.NewInt(2))
+		lowGas   = hexutil.Uint64(params.TxGas + 10)
+	)
+	var testSuite = []struct {
+		overrides *TransactionOverrides
+		expectErr error
+		failed    bool
+		retValue  string
+	}{
+		// Without overrides, the original transaction is traced
+		{
+			overrides: nil,
+			retValue:  fmt.Sprintf("%064x%064x", 1000, baseFee),
+		},
+		// Overridden value and gas price are seen by the execution
+		{
+			overrides: &TransactionOverrides{Value: (*hexutil.Big)(value), GasPrice: (*hexutil.Big)(gasPrice)},
+			retValue:  fmt.Sprintf("%064x%064x", value, gasPrice),
+		},
+		// Overridden gas limit runs out of gas
+		{
+			overrides: &TransactionOverrides{Gas: &lowGas},
+			failed:    true,
+		},
+		// Overridden value exceeds the sender's balance
+		{
+			overrides: &TransactionOverrides{Value: (*hexutil.Big)(big.NewInt(params.Ether))},
+			expectErr: core.ErrInsufficientFunds,
+		},
+	}
+	for i, testspec := range testSuite {
+		result, err := api.TraceTransactionWithOverrides(context.Background(), target, testspec.overrides, nil)
+		if testspec.expectErr != nil {
+			if !errors.Is(err, testspec.expectErr) {
+				t.Errorf("test %d: error mismatch, want %v, have %v", i, testspec.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to trace transaction: %v", i, err)
+			continue
+		}
+		var have *logger.ExecutionResult
+		if err := json.Unmarshal(result.(json.RawMessage), &have); err != nil {
+			t.Errorf("test %d: failed to unmarshal result %v", i, err)
+			continue
+		}
+		if have.Failed != testspec.failed || have.ReturnValue != testspec.retValue {
+			t.Errorf("test %d: result mismatch, want failed %v return %q, have failed %v return %q", i, testspec.failed, testspec.retValue, have.Failed, have.ReturnValue)
+		}
+	}
+}
+
+func TestTraceTransactionByIndex(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts. The contract increments the counter in slot 0
+	// and returns its new value, so every trace depends on the state left by
+	// the transactions preceding it in the block.
+	accounts := newAccounts(1)
+	counter := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			counter:          {Balance: common.Big0, Code: common.Hex2Bytes("6000546001018060005560005260206000f3")},
+		},
+	}
+	var (
+		txHashes []common.Hash
+		signer   = types.HomesteadSigner{}
+	)
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		// Three calls from account[0] to the counter
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, counter, new(big.Int), 100000, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+			txHashes = append(txHashes, tx.Hash())
+		}
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	block1 := rpc.BlockNumberOrHashWithNumber(1)
+	for i, hash := range txHashes {
+		have, err := api.TraceTransactionByIndex(context.Background(), block1, hexutil.Uint(i), nil)
+		if err != nil {
+			t.Fatalf("tx %d: failed to trace by index: %v", i, err)
+		}
+		var res *logger.ExecutionResult
+		if err := json.Unmarshal(have.(json.RawMessage), &res); err != nil {
+			t.Fatalf("tx %d: failed to unmarshal result %v", i, err)
+		}
+		if res.ReturnValue != fmt.Sprintf("%064x", i+1) {
+			t.Errorf("tx %d: counter mismatch, have %s, want %d", i, res.ReturnValue, i+1)
+		}
+		want, err := api.TraceTransaction(context.Background(), hash, nil)
+		if err != nil {
+			t.Fatalf("tx %d: failed to trace by hash: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("tx %d: result mismatch, have %s, want %s", i, have, want)
+		}
+	}
+	var testSuite = []struct {
+		blockNrOrHash rpc.BlockNumberOrHash
+		index         hexutil.Uint
+		expectErr     error
+	}{
+		// Index past the last transaction
+		{
+			blockNrOrHash: block1,
+			index:         3,
+			expectErr:     errors.New("transaction index 3 out of range, block #1 has 3 transactions"),
+		},
+		// Genesis block
+		{
+			blockNrOrHash: rpc.BlockNumberOrHashWithNumber(0),
+			expectErr:     errors.New("genesis is not traceable"),
+		},
+		// Pending block
+		{
+			blockNrOrHash: rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber),
+			expectErr:     errors.New("tracing on top of pending is not supported"),
+		},
+	}
+	for i, testspec := range testSuite {
+		_, err := api.TraceTransactionByIndex(context.Background(), testspec.blockNrOrHash, testspec.index, nil)
+		if err == nil || err.Error() != testspec.expectErr.Error() {
+			t.Errorf("test %d: error mismatch, want %v, have %v", i, testspec.expectErr, err)
+		}
+	}
+	// Unknown block
+	_, err := api.TraceTransactionByIndex(context.Background(), rpc.BlockNumberOrHashWithHash(common.Hash{0x01}, false), 0, nil)
+	if !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("unknown block: want %v, have %v", ErrBlockNotFound, err)
+	}
+}
+
 func TestTraceBlock(t *testing.T) {
 	t.Parallel()
 
@@ -414,7 +670,7 @@ func TestTraceBlock(t *testing.T) {
 		// Trace non-existent block
 		{
 			blockNumber: rpc.BlockNumber(genBlocks + 1),
-			expectErr:   fmt.Errorf("block #%d not found", genBlocks+1),
+			expectErr:   &blockNotFoundError{fmt.Sprintf("block #%d not found", genBlocks+1)},
 		},
 		// Trace latest block
 		{
@@ -451,6 +707,386 @@ func TestTraceBlock(t *testing.T) {
 	}
 }
 
+// stallingTracer is a struct logger which blocks on entering the given
+// recipient until it is forcefully stopped, simulating a pathological trace.
+type stallingTracer struct {
+	*logger.StructLogger
+	target  common.Address
+	stopped chan struct{}
+}
+
+func (t *stallingTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	if to == t.target {
+		<-t.stopped
+	}
+	t.StructLogger.CaptureStart(env, from, to, create, input, gas, value)
+}
+
+func (t *stallingTracer) Stop(err error) {
+	t.StructLogger.Stop(err)
+	close(t.stopped)
+}
+
+// registerStallingTracer adds a tracer named "stallingTracer" to the default
+// directory for the duration of the test, stalling on calls to target.
+func registerStallingTracer(t *testing.T, target common.Address) {
+	DefaultDirectory.Register("stallingTracer", func(ctx *Context, cfg json.RawMessage) (Tracer, error) {
+		return &stallingTracer{
+			StructLogger: logger.NewStructLogger(nil),
+			target:       target,
+			stopped:      make(chan struct{}),
+		}, nil
+	}, false)
+	t.Cleanup(func() { delete(DefaultDirectory.elems, "stallingTracer") })
+}
+
+func TestTraceBlockTimeout(t *testing.T) {
+	// Initialize test accounts
+	accounts := newAccounts(3)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+			accounts[2].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	var (
+		signer   = types.HomesteadSigner{}
+		nonce    uint64
+		txHashes []common.Hash
+	)
+	backend := newTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		// Transfer from account[0] to account[1] in the first block. In the
+		// second one transfer to account[1], account[2] and account[1] again,
+		// the middle one being the transaction that stalls.
+		recipients := []common.Address{accounts[1].addr}
+		if i == 1 {
+			recipients = []common.Address{accounts[1].addr, accounts[2].addr, accounts[1].addr}
+		}
+		for _, to := range recipients {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+			b.AddTx(tx)
+			nonce++
+			if i == 1 {
+				txHashes = append(txHashes, tx.Hash())
+			}
+		}
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	registerStallingTracer(t, accounts[2].addr)
+
+	var (
+		tracer  = "stallingTracer"
+		timeout = "1s"
+		config  = &TraceConfig{Tracer: &tracer, Timeout: &timeout}
+		want    = fmt.Sprintf(`[{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}},{"txHash":"%v","error":"execution timeout"},{"txHash":"%v","result":{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}}]`, txHashes[0], txHashes[1], txHashes[2])
+	)
+	// The stalled transaction is reported and the rest of the block is traced
+	result, err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(2), config)
+	if err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+	if have, _ := json.Marshal(result); string(have) != want {
+		t.Errorf("block result mismatch, have\n%v\n, want\n%v\n", string(have), want)
+	}
+	// Chain tracing behaves the same way
+	from, _ := api.blockByNumber(context.Background(), rpc.BlockNumber(0))
+	to, _ := api.blockByNumber(context.Background(), rpc.BlockNumber(2))
+	var traced []*blockTraceResult
+	for res := range api.traceChain(from, to, config, nil) {
+		traced = append(traced, res)
+	}
+	if len(traced) != 2 {
+		t.Fatalf("unexpected number of traced blocks, have %d want 2", len(traced))
+	}
+	if have, _ := json.Marshal(traced[1].Traces); string(have) != want {
+		t.Errorf("chain result mismatch, have\n%v\n, want\n%v\n", string(have), want)
+	}
+	// An expired caller deadline aborts the whole trace instead
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := api.TraceBlockByNumber(ctx, rpc.BlockNumber(2), config); !errors.Is(err, ErrTraceCancelled) {
+		t.Errorf("want %v, have %v", ErrTraceCancelled, err)
+	}
+	// So does it for the parallel tracer, even if all tasks were handed out
+	block, _ := api.blockByNumber(context.Background(), rpc.BlockNumber(2))
+	parent, _ := api.blockByNumber(context.Background(), rpc.BlockNumber(1))
+	statedb, release, err := backend.StateAtBlock(context.Background(), parent, defaultTraceReexec, nil, true, false)
+	if err != nil {
+		t.Fatalf("failed to retrieve state: %v", err)
+	}
+	defer release()
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := api.traceBlockParallel(ctx, block, statedb, config); !errors.Is(err, ErrTraceCancelled) {
+		t.Errorf("parallel: want %v, have %v", ErrTraceCancelled, err)
+	}
+}
+
+func TestTraceErrors(t *testing.T) {
+	// Initialize test accounts
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	signer := types.HomesteadSigner{}
+	var target common.Hash
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {
+		// Transfer from account[0] to account[1], which stalls the tracer
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+		target = tx.Hash()
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+	registerStallingTracer(t, accounts[1].addr)
+
+	var (
+		stalling = "stallingTracer"
+		unknown  = "unknownTracer"
+		timeout  = "100ms"
+	)
+	// Missing blocks
+	if _, err := api.TraceBlockByHash(context.Background(), common.Hash{0x01}, nil); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("missing block: want %v, have %v", ErrBlockNotFound, err)
+	}
+	// Unknown tracers, no JS evaluator is loaded in this package
+	if _, err := api.TraceTransaction(context.Background(), target, &TraceConfig{Tracer: &unknown}); !errors.Is(err, ErrTracerNotFound) {
+		t.Errorf("unknown tracer: want %v, have %v", ErrTracerNotFound, err)
+	}
+	// Transactions running over their timeout
+	if _, err := api.TraceTransaction(context.Background(), target, &TraceConfig{Tracer: &stalling, Timeout: &timeout}); !errors.Is(err, ErrExecutionTimeout) {
+		t.Errorf("timeout: want %v, have %v", ErrExecutionTimeout, err)
+	}
+	// Requests cancelled by the caller midway
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := api.TraceTransaction(ctx, target, &TraceConfig{Tracer: &stalling}); !errors.Is(err, ErrTraceCancelled) {
+		t.Errorf("cancel: want %v, have %v", ErrTraceCancelled, err)
+	}
+	// Requests whose own deadline expires before the transaction's timeout
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := api.TraceTransaction(ctx, target, &TraceConfig{Tracer: &stalling}); !errors.Is(err, ErrTraceCancelled) {
+		t.Errorf("deadline: want %v, have %v", ErrTraceCancelled, err)
+	}
+}
+
+func TestTraceEmptyBlocks(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts, enabling withdrawals from genesis
+	accounts := newAccounts(1)
+	config := *params.AllEthashProtocolChanges
+	config.TerminalTotalDifficultyPassed = true
+	config.TerminalTotalDifficulty = common.Big0
+	config.ShanghaiTime = new(uint64)
+	genesis := &core.Genesis{
+		Config:     &config,
+		Difficulty: common.Big1,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	// Block 1 is empty, block 2 only carries a withdrawal
+	backend := newTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		if i == 1 {
+			b.AddWithdrawal(&types.Withdrawal{Validator: 5, Address: accounts[0].addr, Amount: 10})
+		}
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	for _, number := range []rpc.BlockNumber{1, 2} {
+		result, err := api.TraceBlockByNumber(context.Background(), number, nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to trace: %v", number, err)
+		}
+		have, _ := json.Marshal(result)
+		if string(have) != "[]" {
+			t.Errorf("block %d: trace mismatch, have %s, want []", number, have)
+		}
+		block, _ := backend.BlockByNumber(context.Background(), number)
+		roots, err := api.IntermediateRoots(context.Background(), block.Hash(), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to trace intermediate roots: %v", number, err)
+		}
+		have, _ = json.Marshal(roots)
+		if string(have) != "[]" {
+			t.Errorf("block %d: roots mismatch, have %s, want []", number, have)
+		}
+	}
+	// Chain tracing skips empty blocks, but always reports the last one
+	from, _ := backend.BlockByNumber(context.Background(), 0)
+	to, _ := backend.BlockByNumber(context.Background(), 2)
+	ret := api.traceChain(from, to, nil, nil)
+	var traced []*blockTraceResult
+	for res := range ret {
+		traced = append(traced, res)
+	}
+	if len(traced) != 1 {
+		t.Fatalf("result length mismatch, have %d, want 1", len(traced))
+	}
+	if traced[0].Block != 2 || traced[0].Traces == nil || len(traced[0].Traces) != 0 {
+		t.Errorf("chain result mismatch, have block %d traces %v, want block 2 with empty traces", traced[0].Block, traced[0].Traces)
+	}
+}
+
+func TestTraceCallMaxDepth(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	// Contract calling itself with all available gas until it runs out:
+	// PUSH1 0 PUSH1 0 PUSH1 0 PUSH1 0 PUSH1 0 ADDRESS GAS CALL STOP
+	overrides := &ethapi.StateOverride{
+		accounts[1].addr: ethapi.OverrideAccount{Code: newRPCBytes(common.Hex2Bytes("60006000600060006000305af100"))},
+	}
+	gas := hexutil.Uint64(100000)
+	call := ethapi.TransactionArgs{From: &accounts[0].addr, To: &accounts[1].addr, Gas: &gas}
+	unlimited, err := api.TraceCall(context.Background(), call, rpc.BlockNumberOrHashWithNumber(1), &TraceCallConfig{StateOverrides: overrides})
+	if err != nil {
+		t.Fatalf("failed to trace call without depth limit: %v", err)
+	}
+	var testSuite = []struct {
+		maxDepth  uint64
+		expectErr error
+	}{
+		{maxDepth: 0, expectErr: ErrMaxDepthExceeded},
+		{maxDepth: 3, expectErr: ErrMaxDepthExceeded},
+		{maxDepth: 1024},
+	}
+	for i, testspec := range testSuite {
+		maxDepth := testspec.maxDepth
+		config := &TraceCallConfig{TraceConfig: TraceConfig{MaxDepth: &maxDepth}, StateOverrides: overrides}
+		result, err := api.TraceCall(context.Background(), call, rpc.BlockNumberOrHashWithNumber(1), config)
+		if testspec.expectErr != nil {
+			if !errors.Is(err, testspec.expectErr) {
+				t.Errorf("test %d: error mismatch, want %v, have %v", i, testspec.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to trace call: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, unlimited) {
+			t.Errorf("test %d: result differs from trace without depth limit", i)
+		}
+	}
+}
+
+// coinbaseTracer is a struct logger which reports the balance of the block's
+// coinbase at the end of the transaction, after the tip has been credited.
+type coinbaseTracer struct {
+	*logger.StructLogger
+	env     *vm.EVM
+	balance *big.Int
+}
+
+func (t *coinbaseTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.env = env
+	t.StructLogger.CaptureStart(env, from, to, create, input, gas, value)
+}
+
+func (t *coinbaseTracer) CaptureTxEnd(restGas uint64) {
+	t.balance = t.env.StateDB.GetBalance(t.env.Context.Coinbase)
+	t.StructLogger.CaptureTxEnd(restGas)
+}
+
+func (t *coinbaseTracer) GetResult() (json.RawMessage, error) {
+	return json.Marshal((*hexutil.Big)(t.balance))
+}
+
+func TestTraceCallCoinbaseOverride(t *testing.T) {
+	// Initialize test accounts
+	accounts := newAccounts(3)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		},
+	}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	DefaultDirectory.Register("coinbaseTracer", func(ctx *Context, cfg json.RawMessage) (Tracer, error) {
+		return &coinbaseTracer{StructLogger: logger.NewStructLogger(nil)}, nil
+	}, false)
+	t.Cleanup(func() { delete(DefaultDirectory.elems, "coinbaseTracer") })
+
+	// Pay a tip equal to the base fee on a plain transfer
+	var (
+		baseFee  = backend.chain.GetHeaderByNumber(1).BaseFee
+		gasPrice = new(big.Int).Mul(baseFee, big.NewInt(2))
+		tip      = new(big.Int).Mul(baseFee, new(big.Int).SetUint64(params.TxGas))
+		tracer   = "coinbaseTracer"
+		coinbase = accounts[2].addr
+		funds    = big.NewInt(params.GWei)
+	)
+	call := ethapi.TransactionArgs{
+		From:     &accounts[0].addr,
+		To:       &accounts[1].addr,
+		Value:    (*hexutil.Big)(big.NewInt(1000)),
+		GasPrice: (*hexutil.Big)(gasPrice),
+	}
+	var testSuite = []struct {
+		config *TraceCallConfig
+		expect *big.Int
+	}{
+		// Fresh fee recipient only holds the tip
+		{
+			config: &TraceCallConfig{
+				TraceConfig:    TraceConfig{Tracer: &tracer},
+				BlockOverrides: &ethapi.BlockOverrides{Coinbase: &coinbase},
+			},
+			expect: tip,
+		},
+		// Funded fee recipient is credited the tip on top of its balance
+		{
+			config: &TraceCallConfig{
+				TraceConfig:    TraceConfig{Tracer: &tracer},
+				StateOverrides: &ethapi.StateOverride{coinbase: ethapi.OverrideAccount{Balance: newRPCBalance(funds)}},
+				BlockOverrides: &ethapi.BlockOverrides{Coinbase: &coinbase},
+			},
+			expect: new(big.Int).Add(funds, tip),
+		},
+	}
+	for i, testspec := range testSuite {
+		result, err := api.TraceCall(context.Background(), call, rpc.BlockNumberOrHashWithNumber(1), testspec.config)
+		if err != nil {
+			t.Errorf("test %d: failed to trace call: %v", i, err)
+			continue
+		}
+		var have hexutil.Big
+		if err := json.Unmarshal(result.(json.RawMessage), &have); err != nil {
+			t.Errorf("test %d: failed to unmarshal result %v", i, err)
+			continue
+		}
+		if have.ToInt().Cmp(testspec.expect) != 0 {
+			t.Errorf("test %d: coinbase balance mismatch, have %v, want %v", i, have.ToInt(), testspec.expect)
+		}
+	}
+}
+
 func TestTracingWithOverrides(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -317,3 +317,21 @@ func TestSetup(t *testing.T) {
 		t.Errorf("tracer returned wrong result. have: %s, want: \"bar\"\n", string(have))
 	}
 }
+
+func TestTracerNotFound(t *testing.T) {
+	// Misspelled tracer names must not be evaluated as JS code
+	for _, name := range []string{"callTraceer", "$unknown_1"} {
+		if _, err := tracers.DefaultDirectory.New(name, new(tracers.Context), nil); !errors.Is(err, tracers.ErrTracerNotFound) {
+			t.Errorf("tracer %q: want %v, have %v", name, tracers.ErrTracerNotFound, err)
+		}
+		if tracers.DefaultDirectory.IsJS(name) {
+			t.Errorf("tracer %q: reported as JS code", name)
+		}
+	}
+	// Registered JS tracers and tracer code still resolve
+	for _, name := range []string{"bigramTracer", "{step: function() {}, fault: function() {}, result: function() {}}"} {
+		if _, err := tracers.DefaultDirectory.New(name, new(tracers.Context), nil); err != nil {
+			t.Errorf("tracer %q: failed to create: %v", name, err)
+		}
+	}
+}
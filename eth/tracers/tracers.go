@@ -53,6 +53,10 @@ type elem struct {
 	isJS bool
 }
 
+// ErrTracerNotFound is returned if the requested tracer is not registered and
+// cannot be interpreted as JS tracer code either.
+var ErrTracerNotFound = errors.New("tracer not found")
+
 // DefaultDirectory is the collection of tracers bundled by default.
 var DefaultDirectory = directory{elems: make(map[string]elem)}
 
@@ -83,7 +87,10 @@ func (d *directory) New(name string, ctx *Context, cfg json.RawMessage) (Tracer,
 	if elem, ok := d.elems[name]; ok {
 		return elem.ctor(ctx, cfg)
 	}
-	// Assume JS code
+	// Assume JS code, unless it's a plain name of a tracer that doesn't exist
+	if d.jsEval == nil || isTracerName(name) {
+		return nil, fmt.Errorf("%w: %s", ErrTracerNotFound, name)
+	}
 	return d.jsEval(name, ctx, cfg)
 }
 
@@ -95,6 +102,24 @@ func (d *directory) IsJS(name string) bool {
 		return elem.isJS
 	}
 	// JS eval will execute JS code
+	return !isTracerName(name)
+}
+
+// isTracerName reports whether the given string is a bare identifier. Such a
+// string names a tracer, as JS code it would only reference a variable.
+func isTracerName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_' || c == '$':
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
 	return true
 }
 